@@ -1,5 +1,7 @@
 package client
 
+import "reflect"
+
 // Call represents an active RPC.
 // 或者理解为一次RPC请求
 type Call struct {
@@ -9,6 +11,13 @@ type Call struct {
 	Reply        interface{} // reply from server
 	Error        error
 	Done         chan *Call // notify when a rpc call is done(get reply from server)
+
+	// streamChan and streamElemType are set by StreamGo: instead of a
+	// single Reply, each intermediate frame is decoded into a fresh
+	// streamElemType value and sent on streamChan, which is closed when
+	// the terminating frame arrives.
+	streamChan     reflect.Value
+	streamElemType reflect.Type
 }
 
 // done is called when this rpc call is done.
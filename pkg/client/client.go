@@ -1,17 +1,29 @@
 package client
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"net/http"
+	"reflect"
 	"rpc-go/pkg/codec"
 	"rpc-go/pkg/option"
 	"sync"
 )
 
+// DefaultRPCPath is the HTTP path DialHTTP CONNECTs to by default; it must
+// match the path the server registered via server.HandleHTTP.
+const DefaultRPCPath = "/_goRPC_"
+
+// connected is the status line a server writes back after a successful
+// CONNECT, mirroring server.ServeHTTP's handshake.
+const connected = "200 Connected to Go RPC"
+
 // Client represents an RPC Client.
 // There may be multiple outstanding Calls associated
 // with a single Client, and a Client may be used by
@@ -28,6 +40,11 @@ type Client struct {
 
 	closing  bool // client side set client is closed
 	shutdown bool // server side set client is closed
+
+	// onClose, if set via OnClose, runs once when this connection shuts
+	// down, whether from Close or from receive() hitting an unrecoverable
+	// error. pool.Pool uses it to evict a dead Client and redial lazily.
+	onClose func(*Client)
 }
 
 var _ io.Closer = (*Client)(nil)
@@ -85,17 +102,51 @@ func (c *Client) removeCall(seq uint64) *Call {
 	return call
 }
 
+// peekCall returns the pending call for seq without removing it, used for
+// streaming calls that stay pending across intermediate frames.
+func (c *Client) peekCall(seq uint64) *Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pending[seq]
+}
+
 func (c *Client) terminalCalls(err error) {
 	c.sending.Lock()
 	defer c.sending.Unlock()
 	c.mu.Lock()
-	defer c.mu.Unlock()
 
 	c.shutdown = true
 	for _, call := range c.pending {
 		call.Error = err
+		if call.streamChan.IsValid() {
+			call.streamChan.Close()
+		}
 		call.done()
 	}
+	onClose := c.onClose
+	c.mu.Unlock()
+
+	// run outside c.mu: onClose typically redials, which registers a brand
+	// new Client and must not be able to deadlock against this one's lock.
+	if onClose != nil {
+		onClose(c)
+	}
+}
+
+// Pending returns how many calls are currently in flight on this
+// connection. pool uses it for least-in-flight load balancing.
+func (c *Client) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// OnClose registers fn to run once, when this connection shuts down,
+// whether from Close or from receive() hitting an unrecoverable error.
+func (c *Client) OnClose(fn func(*Client)) {
+	c.mu.Lock()
+	c.onClose = fn
+	c.mu.Unlock()
 }
 
 // receive blocks until sever return reply.
@@ -107,7 +158,15 @@ func (c *Client) receive() {
 			break
 		}
 
-		call := c.removeCall(h.Seq)
+		more := h.Flags&codec.FlagStreamMore != 0
+
+		var call *Call
+		if more {
+			call = c.peekCall(h.Seq)
+		} else {
+			call = c.removeCall(h.Seq)
+		}
+
 		switch {
 		// it means when client send req err occurs,and call has already been removed
 		case call == nil:
@@ -116,7 +175,27 @@ func (c *Client) receive() {
 			// record err msg
 			call.Error = fmt.Errorf(h.Error)
 			err = c.cc.ReadBody(nil)
+			if more {
+				c.removeCall(h.Seq)
+			}
+			if call.streamChan.IsValid() {
+				call.streamChan.Close()
+			}
+			call.done()
+		case call.streamChan.IsValid() && !more:
+			// the terminating frame's body is an invalidRequest placeholder,
+			// not a streamElemType value, so discard it instead of
+			// type-mismatching the decode (gob in particular rejects it).
+			err = c.cc.ReadBody(nil)
+			call.streamChan.Close()
 			call.done()
+		case call.streamChan.IsValid():
+			elem := reflect.New(call.streamElemType)
+			if err = c.cc.ReadBody(elem.Interface()); err != nil {
+				call.Error = errors.New("reading body " + err.Error())
+				break
+			}
+			call.streamChan.Send(elem.Elem())
 		default:
 			err = c.cc.ReadBody(call.Reply)
 			if err != nil {
@@ -152,6 +231,49 @@ func Dial(network, address string, opts ...*option.Option) (client *Client, err
 	return NewClient(conn, opt)
 }
 
+// DialHTTP connects to an HTTP RPC server listening on the default RPC path
+// at the specified network address.
+func DialHTTP(network, address string, opts ...*option.Option) (*Client, error) {
+	return DialHTTPPath(network, address, DefaultRPCPath, opts...)
+}
+
+// DialHTTPPath connects to an HTTP RPC server listening on the given path
+// at the specified network address, via the net/rpc HTTP CONNECT handshake:
+// it issues a CONNECT request and, once the server confirms by hijacking
+// the connection, runs the normal RPC handshake over it.
+func DialHTTPPath(network, address, path string, opts ...*option.Option) (client *Client, err error) {
+	opt, err := parseOption(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if client == nil {
+			_ = conn.Close()
+		}
+	}()
+
+	_, _ = io.WriteString(conn, "CONNECT "+path+" HTTP/1.0\n\n")
+
+	// require a successful HTTP response before switching to the RPC protocol
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+	if err == nil && resp.Status == connected {
+		return NewClient(conn, opt)
+	}
+	if err == nil {
+		err = errors.New("unexpected HTTP response: " + resp.Status)
+	}
+	return nil, &net.OpError{
+		Op:  "dial-http",
+		Net: network + " " + address,
+		Err: err,
+	}
+}
+
 // Go invokes the function asynchronously.
 // It returns the Call structure representing the invocation.
 func (c *Client) Go(serverMethod string, args, reply interface{}, done chan *Call) *Call {
@@ -170,6 +292,77 @@ func (c *Client) Go(serverMethod string, args, reply interface{}, done chan *Cal
 	return call
 }
 
+// StreamGo invokes a streaming method asynchronously: unlike Go, the server
+// may send any number of replies for this call before it terminates, and
+// each one is decoded into a fresh element and sent on replyChan, which
+// must be a channel of the reply's element type. replyChan is closed when
+// the server's terminating frame arrives, or when the connection is lost.
+func (c *Client) StreamGo(serverMethod string, args, replyChan interface{}, done chan *Call) *Call {
+	chanVal := reflect.ValueOf(replyChan)
+	if chanVal.Kind() != reflect.Chan || chanVal.Type().ChanDir()&reflect.SendDir == 0 {
+		log.Panic("rpc client: replyChan must be a channel you can send on")
+	}
+	if done == nil {
+		done = make(chan *Call, 10)
+	} else if cap(done) == 0 {
+		log.Panic("rpc client: done channel is unbuffered")
+	}
+	call := &Call{
+		ServerMethod:   serverMethod,
+		Args:           args,
+		Done:           done,
+		streamChan:     chanVal,
+		streamElemType: chanVal.Type().Elem(),
+	}
+	c.send(call)
+	return call
+}
+
+// GoContext is like Go, but also watches ctx: if it's done before the
+// server replies, the pending call is removed, Call.Error is set to
+// ctx.Err(), Done is notified, and a Cancel frame is sent so the server can
+// abort its in-flight work too.
+func (c *Client) GoContext(ctx context.Context, serverMethod string, args, reply interface{}, done chan *Call) *Call {
+	call := c.Go(serverMethod, args, reply, done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.cancel(call, ctx.Err())
+		case finished := <-call.Done:
+			// hand the completion back: the caller is waiting on call.Done too
+			call.Done <- finished
+		}
+	}()
+	return call
+}
+
+// CallContext is like Call, but returns ctx.Err() (instead of blocking
+// forever) if ctx is done before the server replies.
+func (c *Client) CallContext(ctx context.Context, serverMethod string, args, reply interface{}) error {
+	call := <-c.GoContext(ctx, serverMethod, args, reply, make(chan *Call, 1)).Done
+	return call.Error
+}
+
+// cancel aborts call: it's removed from pending (a no-op if it already
+// completed), handed err, and a Cancel frame is sent to the server so it
+// can stop whatever work it's doing for call.Seq.
+func (c *Client) cancel(call *Call, err error) {
+	removed := c.removeCall(call.Seq)
+	if removed == nil {
+		return // already completed
+	}
+	removed.Error = err
+	if removed.streamChan.IsValid() {
+		removed.streamChan.Close()
+	}
+	removed.done()
+
+	c.sending.Lock()
+	defer c.sending.Unlock()
+	h := codec.Header{ServerMethod: removed.ServerMethod, Seq: removed.Seq, Type: codec.TypeCancel}
+	_ = c.cc.Write(&h, struct{}{})
+}
+
 func (c *Client) send(call *Call) {
 	// make sure that the client will send a complete request
 	c.sending.Lock()
@@ -0,0 +1,78 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"rpc-go/pkg/client"
+	"rpc-go/pkg/server"
+)
+
+// CtxArith is a service whose method takes a context.Context, used to
+// exercise CallContext's client-side unblock and the server's matching
+// cancellation of the method's ctx.
+type CtxArith struct {
+	cancelled chan struct{}
+}
+
+type CtxArgs struct{ Ms int }
+
+func (c *CtxArith) Sleep(ctx context.Context, args CtxArgs, reply *int) error {
+	select {
+	case <-time.After(time.Duration(args.Ms) * time.Millisecond):
+		*reply = args.Ms
+		return nil
+	case <-ctx.Done():
+		close(c.cancelled)
+		return ctx.Err()
+	}
+}
+
+func TestCallContextTimeout(t *testing.T) {
+	srv := server.NewServer()
+	svc := &CtxArith{cancelled: make(chan struct{})}
+	if err := srv.Registry(svc); err != nil {
+		t.Fatalf("Registry: %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	go srv.Accept(l)
+
+	c, err := client.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	var reply int
+	err = c.CallContext(ctx, "CtxArith.Sleep", CtxArgs{Ms: 1000}, &reply)
+	elapsed := time.Since(start)
+
+	// client-side: CallContext must unblock on its own deadline, not the
+	// service method's much longer sleep.
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("CallContext error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed > 500*time.Millisecond {
+		t.Fatalf("CallContext took %v to unblock, want well under the 1s sleep", elapsed)
+	}
+
+	// server-side: the Cancel frame CallContext sends on timeout must have
+	// propagated into the in-flight method's ctx.
+	select {
+	case <-svc.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("server method never observed ctx cancellation")
+	}
+}
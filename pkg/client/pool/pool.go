@@ -0,0 +1,289 @@
+// Package pool provides a load-balancing, reconnecting pool of RPC clients
+// spread across one or more remote addresses.
+package pool
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+
+	"rpc-go/pkg/client"
+	"rpc-go/pkg/option"
+)
+
+// Strategy picks which connection a call is routed to.
+type Strategy int
+
+const (
+	// RoundRobin cycles through targets in order.
+	RoundRobin Strategy = iota
+	// Random picks a target uniformly at random.
+	Random
+	// LeastInFlight picks the target with the fewest pending calls.
+	LeastInFlight
+)
+
+// PoolOption configures a Pool. See WithXxx functions below.
+type PoolOption func(*Pool)
+
+// WithStrategy sets how Pool picks a connection for each call. The default
+// is RoundRobin.
+func WithStrategy(s Strategy) PoolOption {
+	return func(p *Pool) { p.strategy = s }
+}
+
+// WithMaxIdle sets how many connections NewPool dials per address. The
+// default is 1. Connections that die are not replaced one for one: an
+// address is redialed, with a single connection, only once all of its
+// connections are gone.
+func WithMaxIdle(n int) PoolOption {
+	return func(p *Pool) { p.maxIdle = n }
+}
+
+// WithMaxRetries sets how many other addresses Pool.Call/Pool.Go will try
+// before giving up, after the first pick's connection turns out to be dead.
+// The default is len(addresses)-1, i.e. try every other address once.
+func WithMaxRetries(n int) PoolOption {
+	return func(p *Pool) { p.maxRetries = n }
+}
+
+// WithClientOption sets the option.Option used to dial every connection.
+func WithClientOption(opt *option.Option) PoolOption {
+	return func(p *Pool) { p.opt = opt }
+}
+
+// target tracks every live connection Pool has open to one address.
+type target struct {
+	mu     sync.Mutex
+	addr   string
+	conns  []*client.Client
+	nextRR int // next conns index to hand out under RoundRobin
+}
+
+// pending returns the total number of in-flight calls across every live
+// connection to t, used to compare targets under LeastInFlight.
+func (t *target) pending() int {
+	t.mu.Lock()
+	conns := t.conns
+	t.mu.Unlock()
+
+	n := 0
+	for _, c := range conns {
+		n += c.Pending()
+	}
+	return n
+}
+
+// Pool is a load-balancing, reconnecting set of Client connections spread
+// across one or more remote addresses on a single network. A Pool is safe
+// for concurrent use by multiple goroutines.
+type Pool struct {
+	network string
+	opt     *option.Option
+
+	strategy   Strategy
+	maxIdle    int
+	maxRetries int
+
+	mu      sync.Mutex
+	targets []*target
+	closed  bool
+	nextRR  int // next targets index to hand out under RoundRobin
+	lastIdx int // targets index picked for the current call's attempt 0
+}
+
+// NewPool dials maxIdle connections to each of addresses and returns a Pool
+// that load-balances calls across them, transparently redialing any
+// connection that dies.
+func NewPool(network string, addresses []string, opts ...PoolOption) (*Pool, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("rpc pool: no addresses")
+	}
+
+	p := &Pool{
+		network:    network,
+		maxIdle:    1,
+		maxRetries: len(addresses) - 1,
+	}
+	for _, o := range opts {
+		o(p)
+	}
+
+	for _, addr := range addresses {
+		t := &target{addr: addr}
+		for i := 0; i < p.maxIdle; i++ {
+			c, err := p.dial(t)
+			if err != nil {
+				log.Println("rpc pool: dial", addr, "error:", err)
+				continue
+			}
+			t.conns = append(t.conns, c)
+		}
+		p.targets = append(p.targets, t)
+	}
+
+	return p, nil
+}
+
+// dial connects a new Client to t.addr and arranges for it to be evicted
+// from t.conns once it closes, so a later pick redials lazily instead of
+// handing out a dead connection.
+func (p *Pool) dial(t *target) (*client.Client, error) {
+	c, err := client.Dial(p.network, t.addr, p.opt)
+	if err != nil {
+		return nil, err
+	}
+	c.OnClose(func(dead *client.Client) {
+		t.mu.Lock()
+		for i, existing := range t.conns {
+			if existing == dead {
+				t.conns = append(t.conns[:i], t.conns[i+1:]...)
+				break
+			}
+		}
+		t.mu.Unlock()
+	})
+	return c, nil
+}
+
+// pick returns a live connection to t, redialing if every connection
+// previously held for t has died.
+func (p *Pool) pick(t *target) (*client.Client, error) {
+	t.mu.Lock()
+	if len(t.conns) == 0 {
+		t.mu.Unlock()
+		c, err := p.dial(t)
+		if err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.conns = append(t.conns, c)
+		t.mu.Unlock()
+		return c, nil
+	}
+	defer t.mu.Unlock()
+
+	switch p.strategy {
+	case Random:
+		return t.conns[rand.Intn(len(t.conns))], nil
+	case LeastInFlight:
+		best := t.conns[0]
+		for _, c := range t.conns[1:] {
+			if c.Pending() < best.Pending() {
+				best = c
+			}
+		}
+		return best, nil
+	default: // RoundRobin
+		c := t.conns[t.nextRR%len(t.conns)]
+		t.nextRR++
+		return c, nil
+	}
+}
+
+// pickTarget returns the target to try for this attempt. attempt 0 is the
+// primary pick, made according to Pool's strategy; attempt > 0 is a
+// failover after the previous attempt's connection turned out to be dead,
+// and simply advances to the next target after the one that failed.
+func (p *Pool) pickTarget(attempt int) *target {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := len(p.targets)
+	if attempt > 0 {
+		return p.targets[(p.lastIdx+attempt)%n]
+	}
+
+	var idx int
+	switch p.strategy {
+	case Random:
+		idx = rand.Intn(n)
+	case LeastInFlight:
+		idx = p.leastInFlightIndexLocked()
+	default: // RoundRobin
+		idx = p.nextRR % n
+		p.nextRR++
+	}
+	p.lastIdx = idx
+	return p.targets[idx]
+}
+
+// leastInFlightIndexLocked returns the index of the target whose live
+// connections have the fewest calls in flight in aggregate. Called with
+// p.mu held.
+func (p *Pool) leastInFlightIndexLocked() int {
+	best := 0
+	bestPending := p.targets[0].pending()
+	for i, t := range p.targets[1:] {
+		if n := t.pending(); n < bestPending {
+			best, bestPending = i+1, n
+		}
+	}
+	return best
+}
+
+// Call picks a connection, according to Pool's strategy, and makes the RPC
+// on it. If that connection is dead, Call fails over to another address, up
+// to maxRetries times, before giving up.
+func (p *Pool) Call(serviceMethod string, args, reply interface{}) error {
+	var err error
+	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+		t := p.pickTarget(attempt)
+		var c *client.Client
+		c, err = p.pick(t)
+		if err != nil {
+			continue
+		}
+		err = c.Call(serviceMethod, args, reply)
+		if !errors.Is(err, client.ErrShutdown) {
+			return err
+		}
+	}
+	return err
+}
+
+// Go is like Call, but asynchronous: it picks a connection the same way
+// Call does and returns its Call immediately. Go only fails over at
+// connection-acquisition time — if the pick is dead it tries the next
+// target — not for errors discovered later on a call already in flight.
+func (p *Pool) Go(serviceMethod string, args, reply interface{}, done chan *client.Call) *client.Call {
+	var lastErr error
+	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+		t := p.pickTarget(attempt)
+		c, err := p.pick(t)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return c.Go(serviceMethod, args, reply, done)
+	}
+
+	call := &client.Call{ServerMethod: serviceMethod, Args: args, Reply: reply, Done: done}
+	call.Error = lastErr
+	if call.Done == nil {
+		call.Done = make(chan *client.Call, 10)
+	}
+	call.Done <- call
+	return call
+}
+
+// Close closes every connection Pool currently holds open.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	targets := p.targets
+	p.mu.Unlock()
+
+	var firstErr error
+	for _, t := range targets {
+		t.mu.Lock()
+		conns := t.conns
+		t.mu.Unlock()
+		for _, c := range conns {
+			if err := c.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,126 @@
+package pool
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"rpc-go/pkg/server"
+)
+
+// EchoArith is a trivial service used to give Pool something to call; hits
+// counts how many Echo and Slow calls this particular instance completed, so
+// a test can tell which of several servers actually handled a call.
+type EchoArith struct {
+	hits int64
+
+	// started and release, if non-nil, let a test hold one Slow call open
+	// to make its connection's Pending() count observably nonzero.
+	started chan struct{}
+	release chan struct{}
+}
+
+type EchoArgs struct{ A int }
+
+func (e *EchoArith) Echo(args EchoArgs, reply *int) error {
+	atomic.AddInt64(&e.hits, 1)
+	*reply = args.A
+	return nil
+}
+
+// Slow signals started, then blocks until release, so a test can keep a
+// call in flight on this server for as long as it likes.
+func (e *EchoArith) Slow(args EchoArgs, reply *int) error {
+	close(e.started)
+	<-e.release
+	atomic.AddInt64(&e.hits, 1)
+	*reply = args.A
+	return nil
+}
+
+func newTestServer(t *testing.T) (addr string, svc *EchoArith) {
+	t.Helper()
+	svc = &EchoArith{started: make(chan struct{}), release: make(chan struct{})}
+	srv := server.NewServer()
+	if err := srv.Registry(svc); err != nil {
+		t.Fatalf("Registry: %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go srv.Accept(l)
+	return l.Addr().String(), svc
+}
+
+func TestPoolRoundRobinDistributesAcrossTargets(t *testing.T) {
+	addr1, svc1 := newTestServer(t)
+	addr2, svc2 := newTestServer(t)
+
+	p, err := NewPool("tcp", []string{addr1, addr2})
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+	defer p.Close()
+
+	for i := 0; i < 10; i++ {
+		var reply int
+		if err := p.Call("EchoArith.Echo", EchoArgs{A: i}, &reply); err != nil {
+			t.Fatalf("Call: %v", err)
+		}
+	}
+
+	h1, h2 := atomic.LoadInt64(&svc1.hits), atomic.LoadInt64(&svc2.hits)
+	if h1 == 0 || h2 == 0 {
+		t.Fatalf("RoundRobin did not distribute across targets, got hits %d/%d", h1, h2)
+	}
+	if h1 != h2 {
+		t.Fatalf("RoundRobin hits not evenly split, got %d/%d", h1, h2)
+	}
+}
+
+func TestPoolLeastInFlightPrefersIdleTarget(t *testing.T) {
+	addr1, svc1 := newTestServer(t)
+	addr2, svc2 := newTestServer(t)
+
+	p, err := NewPool("tcp", []string{addr1, addr2}, WithStrategy(LeastInFlight))
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+	defer p.Close()
+
+	// Pin a call in flight on whichever target is picked first (both start
+	// idle, so this is targets[0]), then release it once the test is done
+	// probing which target the next pick lands on.
+	done := make(chan error, 1)
+	go func() {
+		var reply int
+		done <- p.Call("EchoArith.Slow", EchoArgs{A: 0}, &reply)
+	}()
+	select {
+	case <-svc1.started:
+	case <-svc2.started:
+	case <-time.After(time.Second):
+		t.Fatal("Slow call never started")
+	}
+
+	// With one target showing a pending call, LeastInFlight must route this
+	// one to the other, idle target.
+	var reply int
+	if err := p.Call("EchoArith.Echo", EchoArgs{A: 1}, &reply); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+
+	close(svc1.release)
+	close(svc2.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Slow call: %v", err)
+	}
+
+	h1, h2 := atomic.LoadInt64(&svc1.hits), atomic.LoadInt64(&svc2.hits)
+	if h1 != 1 || h2 != 1 {
+		t.Fatalf("want the Slow call and the Echo call on different targets, got hits %d/%d", h1, h2)
+	}
+}
@@ -9,8 +9,38 @@ type Header struct {
 	// Seq is an id to identify a client.
 	Seq   uint64
 	Error string
+	// Flags carries out-of-band framing bits. The zero value means a
+	// plain, non-streaming request/response, so Headers encoded before
+	// streaming existed still decode the same way.
+	Flags HeaderFlag
+	// Type distinguishes a Request from a Cancel control frame. The zero
+	// value is Request, so Headers encoded before this field existed still
+	// decode as plain requests.
+	Type HeaderType
 }
 
+// HeaderFlag marks special framing of a Header beyond a plain request/response.
+type HeaderFlag uint8
+
+const (
+	// FlagStreamMore marks a response as one of possibly many replies to a
+	// streaming call; the frame that terminates the stream has it clear.
+	FlagStreamMore HeaderFlag = 1 << iota
+)
+
+// HeaderType says what kind of frame a Header introduces.
+type HeaderType uint8
+
+const (
+	// TypeRequest is a normal client call, awaiting a Response.
+	TypeRequest HeaderType = iota
+	// TypeResponse is a server reply to a Request.
+	TypeResponse
+	// TypeCancel asks the server to abort the in-flight call with this Seq;
+	// it carries no meaningful body and gets no Response.
+	TypeCancel
+)
+
 // Codec define codec to encode and decode message.
 type Codec interface {
 	io.Closer
@@ -33,4 +63,5 @@ var NewCodecFuncMap map[Type]NewCodecFunc
 func init() {
 	NewCodecFuncMap = make(map[Type]NewCodecFunc)
 	NewCodecFuncMap[GobType] = NewGobCodec
+	NewCodecFuncMap[JsonType] = NewJsonCodec
 }
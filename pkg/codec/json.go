@@ -0,0 +1,188 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+)
+
+// JsonCodec is an implementation of Codec using JSON. Its wire format is
+// deliberately compatible with the stdlib net/rpc/jsonrpc codec: a request
+// looks like {"method","params","id"} with params a one-element array, and
+// a response looks like {"id","result","error"}. This lets a plain
+// net/rpc/jsonrpc client talk to Server, and lets this package's own
+// Client/Server pair talk JSON to each other.
+type JsonCodec struct {
+	conn io.ReadWriteCloser
+	buf  *bufio.Writer
+	dec  *json.Decoder
+	enc  *json.Encoder
+
+	mu      sync.Mutex          // guards pending
+	pending map[uint64]struct{} // Seq of requests read but not yet responded to
+
+	bodyMu  sync.Mutex       // guards lastReq/lastRes, set by ReadHeader, consumed by ReadBody
+	lastReq *json.RawMessage // params of the request ReadHeader just decoded
+	lastRes *json.RawMessage // result of the response ReadHeader just decoded
+}
+
+var _ Codec = (*JsonCodec)(nil)
+
+// jsonRequest is the shape written for an outgoing request.
+type jsonRequest struct {
+	Method string         `json:"method"`
+	Params [1]interface{} `json:"params"`
+	Id     uint64         `json:"id"`
+	// Type is omitted for a plain request so older decoders still see a
+	// well-formed net/rpc/jsonrpc request; it's only set for a Cancel frame.
+	Type HeaderType `json:"type,omitempty"`
+}
+
+// jsonResponse is the shape written for an outgoing response.
+type jsonResponse struct {
+	Id     uint64      `json:"id"`
+	Result interface{} `json:"result"`
+	Error  interface{} `json:"error"`
+	// Flags is omitted for plain responses so older decoders that don't
+	// know about it still see a well-formed net/rpc/jsonrpc response.
+	Flags HeaderFlag `json:"flags,omitempty"`
+}
+
+// incomingFrame is decoded for every inbound message so we can tell, just
+// from the fields present, whether it's a request or a response before
+// knowing which shape its payload should be unmarshalled as.
+type incomingFrame struct {
+	Method string           `json:"method"`
+	Params *json.RawMessage `json:"params"`
+	Id     uint64           `json:"id"`
+	Result *json.RawMessage `json:"result"`
+	Error  interface{}      `json:"error"`
+	Flags  HeaderFlag       `json:"flags,omitempty"`
+	Type   HeaderType       `json:"type,omitempty"`
+}
+
+// NewJsonCodec returns a new JsonCodec wrapping conn.
+func NewJsonCodec(conn io.ReadWriteCloser) Codec {
+	buf := bufio.NewWriter(conn)
+	return &JsonCodec{
+		conn:    conn,
+		buf:     buf,
+		dec:     json.NewDecoder(conn),
+		enc:     json.NewEncoder(buf),
+		pending: make(map[uint64]struct{}),
+	}
+}
+
+func (c *JsonCodec) Close() error {
+	return c.conn.Close()
+}
+
+func (c *JsonCodec) ReadHeader(header *Header) error {
+	var frame incomingFrame
+	if err := c.dec.Decode(&frame); err != nil {
+		return err
+	}
+
+	if frame.Method != "" {
+		// this is a request: remember we owe its Id a response, and stash
+		// its params so the following ReadBody knows what to decode. A
+		// Cancel frame gets no response, so it's never added — otherwise
+		// its Id would sit in pending forever.
+		if frame.Type != TypeCancel {
+			c.mu.Lock()
+			c.pending[frame.Id] = struct{}{}
+			c.mu.Unlock()
+		}
+
+		c.bodyMu.Lock()
+		c.lastReq, c.lastRes = frame.Params, nil
+		c.bodyMu.Unlock()
+
+		header.ServerMethod = frame.Method
+		header.Seq = frame.Id
+		header.Error = ""
+		header.Type = frame.Type
+		return nil
+	}
+
+	// this is a response
+	c.bodyMu.Lock()
+	c.lastReq, c.lastRes = nil, frame.Result
+	c.bodyMu.Unlock()
+
+	header.ServerMethod = ""
+	header.Seq = frame.Id
+	header.Error = ""
+	header.Flags = frame.Flags
+	if frame.Error != nil {
+		if msg, ok := frame.Error.(string); ok {
+			header.Error = msg
+		} else if b, err := json.Marshal(frame.Error); err == nil {
+			header.Error = string(b)
+		}
+	}
+	return nil
+}
+
+func (c *JsonCodec) ReadBody(body interface{}) error {
+	c.bodyMu.Lock()
+	req, res := c.lastReq, c.lastRes
+	c.lastReq, c.lastRes = nil, nil
+	c.bodyMu.Unlock()
+
+	if body == nil {
+		return nil
+	}
+	switch {
+	case req != nil:
+		var params [1]json.RawMessage
+		if err := json.Unmarshal(*req, &params); err != nil {
+			return err
+		}
+		return json.Unmarshal(params[0], body)
+	case res != nil:
+		return json.Unmarshal(*res, body)
+	default:
+		return nil
+	}
+}
+
+func (c *JsonCodec) Write(header *Header, body interface{}) (err error) {
+	defer func() {
+		_ = c.buf.Flush()
+		if err != nil {
+			_ = c.Close()
+		}
+	}()
+
+	c.mu.Lock()
+	_, isResponse := c.pending[header.Seq]
+	// a streaming response keeps its Seq pending across intermediate
+	// frames; only the terminating frame (Flags clear) retires it.
+	if isResponse && header.Flags&FlagStreamMore == 0 {
+		delete(c.pending, header.Seq)
+	}
+	c.mu.Unlock()
+
+	if isResponse {
+		resp := jsonResponse{Id: header.Seq, Flags: header.Flags}
+		if header.Error != "" {
+			resp.Error = header.Error
+		} else {
+			resp.Result = body
+		}
+		if err = c.enc.Encode(&resp); err != nil {
+			log.Println("rpc: json error encoding response:", err)
+		}
+		return err
+	}
+
+	req := jsonRequest{Method: header.ServerMethod, Id: header.Seq, Type: header.Type}
+	req.Params[0] = body
+	if err = c.enc.Encode(&req); err != nil {
+		log.Println("rpc: json error encoding request:", err)
+	}
+	return err
+}
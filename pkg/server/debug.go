@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+)
+
+const debugText = `<html>
+	<body>
+	<title>RPC Services</title>
+	{{range .}}
+	<hr>
+	Service {{.Name}}
+	<hr>
+		<table>
+		<th align=center>Method</th><th align=center>Type</th><th align=center>Calls</th>
+		{{range .Method}}
+			<tr>
+			<td align=left font=fixed>{{.Name}}</td>
+			<td align=left font=fixed>func({{.ArgType}}, {{.ReplyType}}) error</td>
+			<td align=center>{{.NumCalls}}</td>
+			</tr>
+		{{end}}
+		</table>
+	{{end}}
+	</body>
+	</html>`
+
+var debugTemplate = template.Must(template.New("RPC debug").Parse(debugText))
+
+// debugMethod is one row of the debug page's method table.
+type debugMethod struct {
+	Name      string
+	ArgType   string
+	ReplyType string
+	NumCalls  uint64
+}
+
+// debugService is one service's section of the debug page.
+type debugService struct {
+	Name   string
+	Method []debugMethod
+}
+
+// debugHTTP implements http.Handler, rendering a table of every registered
+// service's methods and their invocation counts.
+type debugHTTP struct {
+	*Server
+}
+
+func (d debugHTTP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var services []debugService
+
+	d.serviceMap.Range(func(_, svci interface{}) bool {
+		svc := svci.(*service)
+		ds := debugService{Name: svc.name}
+		for name, mtype := range svc.method {
+			replyType := "stream"
+			if mtype.ReplyType != nil {
+				replyType = mtype.ReplyType.String()
+			}
+			ds.Method = append(ds.Method, debugMethod{
+				Name:      name,
+				ArgType:   mtype.ArgType.String(),
+				ReplyType: replyType,
+				NumCalls:  mtype.NumCalls(),
+			})
+		}
+		sort.Slice(ds.Method, func(i, j int) bool { return ds.Method[i].Name < ds.Method[j].Name })
+		services = append(services, ds)
+		return true
+	})
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	if err := debugTemplate.Execute(w, services); err != nil {
+		_, _ = fmt.Fprintln(w, "rpc: error executing template:", err)
+	}
+}
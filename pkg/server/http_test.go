@@ -0,0 +1,107 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"rpc-go/pkg/client"
+)
+
+// DebugArith is a trivial service used to exercise HandleHTTP end to end:
+// dial over HTTP CONNECT, make a call, and scrape the debug page.
+type DebugArith int
+
+type DebugArgs struct{ A, B int }
+
+func (DebugArith) Add(args DebugArgs, reply *int) error {
+	*reply = args.A + args.B
+	return nil
+}
+
+func TestHandleHTTPAndDebugPage(t *testing.T) {
+	srv := NewServer()
+	if err := srv.Registry(new(DebugArith)); err != nil {
+		t.Fatalf("Registry: %v", err)
+	}
+
+	// Mounted on a private mux, rather than via srv.HandleHTTP on the
+	// process-wide http.DefaultServeMux, so the test is safe to rerun
+	// (e.g. go test -count=2) without a duplicate-registration panic.
+	const rpcPath, debugPath = "/_goRPC_test", "/debug/rpc_test"
+	mux := http.NewServeMux()
+	mux.Handle(rpcPath, srv)
+	mux.Handle(debugPath, debugHTTP{srv})
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	c, err := client.DialHTTPPath("tcp", addr, rpcPath)
+	if err != nil {
+		t.Fatalf("DialHTTPPath: %v", err)
+	}
+	defer c.Close()
+
+	var reply int
+	if err := c.Call("DebugArith.Add", DebugArgs{A: 3, B: 4}, &reply); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if reply != 7 {
+		t.Fatalf("DebugArith.Add = %d, want 7", reply)
+	}
+
+	resp, err := http.Get(ts.URL + debugPath)
+	if err != nil {
+		t.Fatalf("GET debug page: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read debug page: %v", err)
+	}
+
+	page := string(body)
+	if !strings.Contains(page, "DebugArith") || !strings.Contains(page, "Add") {
+		t.Fatalf("debug page missing registered service/method, got:\n%s", page)
+	}
+}
+
+// DebugCounter is a streaming service, whose methodType.ReplyType is nil;
+// the debug page must render it without panicking.
+type DebugCounter int
+
+func (DebugCounter) Count(args DebugArgs, stream *Stream) error {
+	return stream.Send(args.A)
+}
+
+func TestDebugPageWithStreamingMethod(t *testing.T) {
+	srv := NewServer()
+	if err := srv.Registry(new(DebugCounter)); err != nil {
+		t.Fatalf("Registry: %v", err)
+	}
+
+	const debugPath = "/debug/rpc_stream_test"
+	mux := http.NewServeMux()
+	mux.Handle(debugPath, debugHTTP{srv})
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + debugPath)
+	if err != nil {
+		t.Fatalf("GET debug page: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read debug page: %v", err)
+	}
+
+	page := string(body)
+	if !strings.Contains(page, "DebugCounter") || !strings.Contains(page, "stream") {
+		t.Fatalf("debug page missing streaming method placeholder, got:\n%s", page)
+	}
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"context"
+
+	"rpc-go/pkg/codec"
+)
+
+// Request is the public view of an in-flight call that a Middleware can
+// inspect: which service/method it targets, the wire header, and the
+// already-decoded argument.
+type Request struct {
+	ServiceName string
+	MethodName  string
+	Header      *codec.Header
+	Arg         interface{}
+}
+
+// Handler processes one call, filling reply or returning an error instead.
+type Handler func(ctx context.Context, req *Request, reply interface{}) error
+
+// Middleware wraps a Handler to add cross-cutting behavior (logging, auth,
+// rate limiting, metrics, ...) around it.
+type Middleware func(Handler) Handler
+
+// Use registers middlewares to run, in order, around every non-streaming
+// call. They compose like an onion: the first registered Middleware is the
+// outermost, so it sees the call first and the reply last.
+func (s *Server) Use(mw ...Middleware) {
+	s.middlewares = append(s.middlewares, mw...)
+}
+
+// chain composes s.middlewares around terminal into a single Handler.
+func (s *Server) chain(terminal Handler) Handler {
+	h := terminal
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		h = s.middlewares[i](h)
+	}
+	return h
+}
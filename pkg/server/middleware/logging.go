@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"rpc-go/pkg/server"
+)
+
+// Logging returns a Middleware that logs each call's service/method, Seq,
+// how long it took, and any error it returned.
+func Logging() server.Middleware {
+	return func(next server.Handler) server.Handler {
+		return func(ctx context.Context, req *server.Request, reply interface{}) error {
+			start := time.Now()
+			err := next(ctx, req, reply)
+			log.Printf("rpc server: %s.%s seq=%d took=%s err=%v",
+				req.ServiceName, req.MethodName, req.Header.Seq, time.Since(start), err)
+			return err
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"rpc-go/pkg/server"
+)
+
+// Counter is a Prometheus-style monotonic counter keyed by "service.method".
+type Counter struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+func NewCounter() *Counter {
+	return &Counter{counts: make(map[string]uint64)}
+}
+
+func (c *Counter) Inc(key string) {
+	c.mu.Lock()
+	c.counts[key]++
+	c.mu.Unlock()
+}
+
+// Get returns key's current count, mainly useful to cross-check against the
+// matching service's methodType.NumCalls() in tests.
+func (c *Counter) Get(key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[key]
+}
+
+// Histogram is a minimal latency histogram keyed by "service.method",
+// recording every observed duration for later quantile calculation.
+type Histogram struct {
+	mu  sync.Mutex
+	obs map[string][]time.Duration
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{obs: make(map[string][]time.Duration)}
+}
+
+func (h *Histogram) Observe(key string, d time.Duration) {
+	h.mu.Lock()
+	h.obs[key] = append(h.obs[key], d)
+	h.mu.Unlock()
+}
+
+// Observations returns a copy of every duration recorded for key.
+func (h *Histogram) Observations(key string) []time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]time.Duration, len(h.obs[key]))
+	copy(out, h.obs[key])
+	return out
+}
+
+// Metrics returns a Middleware that increments calls and observes latency
+// for every "service.method". Cross-check calls.Get(key) against the
+// service's own methodType.NumCalls() to confirm the two agree.
+func Metrics(calls *Counter, latency *Histogram) server.Middleware {
+	return func(next server.Handler) server.Handler {
+		return func(ctx context.Context, req *server.Request, reply interface{}) error {
+			start := time.Now()
+			err := next(ctx, req, reply)
+			key := req.ServiceName + "." + req.MethodName
+			calls.Inc(key)
+			latency.Observe(key, time.Since(start))
+			return err
+		}
+	}
+}
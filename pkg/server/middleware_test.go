@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"rpc-go/pkg/client"
+)
+
+type MWArith int
+
+type MWArgs struct{ A, B int }
+
+func (MWArith) Add(args MWArgs, reply *int) error {
+	*reply = args.A + args.B
+	return nil
+}
+
+// trace returns a Middleware that appends name to order before and after
+// calling next, so a test can assert the onion ordering Use documents.
+func trace(order *[]string, name string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request, reply interface{}) error {
+			*order = append(*order, name+":before")
+			err := next(ctx, req, reply)
+			*order = append(*order, name+":after")
+			return err
+		}
+	}
+}
+
+func TestMiddlewareRunsInRegisteredOrder(t *testing.T) {
+	srv := NewServer()
+	if err := srv.Registry(new(MWArith)); err != nil {
+		t.Fatalf("Registry: %v", err)
+	}
+
+	var order []string
+	srv.Use(trace(&order, "outer"), trace(&order, "inner"))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	go srv.Accept(l)
+
+	c, err := client.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	var reply int
+	if err := c.Call("MWArith.Add", MWArgs{A: 2, B: 3}, &reply); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if reply != 5 {
+		t.Fatalf("reply = %d, want 5", reply)
+	}
+
+	want := []string{"outer:before", "inner:before", "inner:after", "outer:after"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestMiddlewareShortCircuitsTerminal(t *testing.T) {
+	srv := NewServer()
+	if err := srv.Registry(new(MWArith)); err != nil {
+		t.Fatalf("Registry: %v", err)
+	}
+
+	denied := errors.New("denied")
+	srv.Use(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request, reply interface{}) error {
+			return denied
+		}
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	go srv.Accept(l)
+
+	c, err := client.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	var reply int
+	err = c.Call("MWArith.Add", MWArgs{A: 2, B: 3}, &reply)
+	if err == nil || err.Error() != denied.Error() {
+		t.Fatalf("Call error = %v, want %v", err, denied)
+	}
+}
@@ -1,11 +1,14 @@
 package server
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"log"
 	"net"
+	"net/http"
 	"reflect"
 	"rpc-go/pkg/option"
 	"strings"
@@ -14,9 +17,29 @@ import (
 	"rpc-go/pkg/codec"
 )
 
+const (
+	// DefaultRPCPath is the HTTP path Server.ServeHTTP listens on by default.
+	DefaultRPCPath = "/_goRPC_"
+	// DefaultDebugPath is the HTTP path the service debug page is served on by default.
+	DefaultDebugPath = "/debug/rpc"
+	// connected is the status line written back to a client after a
+	// successful CONNECT, mirroring net/rpc's HTTP hijacking handshake.
+	connected = "200 Connected to Go RPC"
+)
+
+// rwc glues a separate reader, writer and closer into one io.ReadWriteCloser.
+// ServeConn uses it to keep reading through the buffered reader it peeked
+// the preamble with, while still writing to and closing the raw conn.
+type rwc struct {
+	io.Reader
+	io.Writer
+	io.Closer
+}
+
 // Server is rpc server
 type Server struct {
-	serviceMap sync.Map
+	serviceMap  sync.Map
+	middlewares []Middleware
 }
 
 func NewServer() *Server {
@@ -36,6 +59,7 @@ func (s *Server) Accept(lis net.Listener) {
 		conn, err := lis.Accept()
 		if err != nil {
 			log.Println("rpc server: accept error:", err)
+			return
 		}
 		s.ServeConn(conn)
 	}
@@ -79,32 +103,72 @@ func (s *Server) findService(serviceMethod string) (svc *service, mtype *methodT
 
 // ServeConn process each connection
 // it blocks until client comes new request.
+//
+// A connection normally opens with a negotiated Option so Server knows
+// which codec to use. A plain net/rpc/jsonrpc client never sends that
+// preamble though, so we peek the first line: if it doesn't parse as an
+// Option with our MagicNumber, we treat it as the client's first jsonrpc
+// request and fall back to raw jsonrpc framing without codec negotiation.
+//
+// The peek reads exactly one line rather than decoding straight off br,
+// because both our Option and every net/rpc/jsonrpc request are written
+// with json.Encoder, which always terminates the value with a single
+// newline; stopping there leaves br positioned right after it, so the
+// codec built on br next neither loses bytes coalesced into the same read
+// nor sees a stray trailing newline corrupt its framing (gob in
+// particular has none of JSON's self-delimiting syntax to resync on).
 func (s *Server) ServeConn(conn io.ReadWriteCloser) {
 	defer func() {
 		_ = conn.Close()
 	}()
 
+	br := bufio.NewReader(conn)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		log.Println("rpc server: options error:", err)
+		return
+	}
+
 	var opt option.Option
-	// use json here because we need to get concrete codec first
-	if err := json.NewDecoder(conn).Decode(&opt); err != nil {
-		log.Printf("rpc server: invalid magic number %x", opt.MagicNumber)
+	if err := json.Unmarshal([]byte(line), &opt); err != nil || opt.MagicNumber != option.MagicNumber {
+		log.Println("rpc server: no valid Option preamble, falling back to raw jsonrpc")
+		s.serveCodec(codec.NewJsonCodec(&rwc{
+			Reader: io.MultiReader(strings.NewReader(line), br),
+			Writer: conn,
+			Closer: conn,
+		}))
 		return
 	}
+
 	f := codec.NewCodecFuncMap[opt.CodecType]
 	if f == nil {
 		log.Printf("rpc server: invalid codec type %s", opt.CodecType)
 		return
 	}
-	s.serveCodec(f(conn))
+	s.serveCodec(f(&rwc{Reader: br, Writer: conn, Closer: conn}))
 }
 
 func (s *Server) serveCodec(cc codec.Codec) {
 	// each goroutine process each request so mutex is needed for concurrent request process
 	sending := new(sync.Mutex)
 	wg := new(sync.WaitGroup)
+	cancels := newCancelRegistry()
 
 	for {
-		req, err := s.readRequest(cc)
+		h, err := s.readRequestHeader(cc)
+		if err != nil {
+			break // it's not possible to recover, so close the connection
+		}
+
+		if h.Type == codec.TypeCancel {
+			// carries no meaningful body, but still has to be consumed to
+			// keep two-phase codecs (e.g. gob) in sync
+			_ = cc.ReadBody(nil)
+			cancels.cancel(h.Seq)
+			continue
+		}
+
+		req, err := s.readRequestBody(cc, h)
 		if err != nil {
 			if req == nil {
 				break // it's not possible to recover, so close the connection
@@ -113,13 +177,51 @@ func (s *Server) serveCodec(cc codec.Codec) {
 			s.sendResponse(cc, req.header, invalidRequest, sending)
 			continue
 		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels.set(h.Seq, cancel)
 		wg.Add(1)
-		go s.handleRequest(cc, req, sending, wg)
+		go func() {
+			defer cancels.delete(h.Seq)
+			s.handleRequest(ctx, cc, req, sending, wg)
+		}()
 	}
 	wg.Wait()
 	_ = cc.Close()
 }
 
+// cancelRegistry tracks the context.CancelFunc for each in-flight request on
+// a connection, keyed by its Seq, so a Cancel frame can abort it.
+type cancelRegistry struct {
+	mu    sync.Mutex
+	funcs map[uint64]context.CancelFunc
+}
+
+func newCancelRegistry() *cancelRegistry {
+	return &cancelRegistry{funcs: make(map[uint64]context.CancelFunc)}
+}
+
+func (r *cancelRegistry) set(seq uint64, cancel context.CancelFunc) {
+	r.mu.Lock()
+	r.funcs[seq] = cancel
+	r.mu.Unlock()
+}
+
+func (r *cancelRegistry) delete(seq uint64) {
+	r.mu.Lock()
+	delete(r.funcs, seq)
+	r.mu.Unlock()
+}
+
+func (r *cancelRegistry) cancel(seq uint64) {
+	r.mu.Lock()
+	cancel := r.funcs[seq]
+	r.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
 type request struct {
 	header *codec.Header
 	argv   reflect.Value
@@ -128,19 +230,18 @@ type request struct {
 	svc    *service
 }
 
-func (s *Server) readRequest(cc codec.Codec) (*request, error) {
-	h, err := s.readRequestHeader(cc)
-	if err != nil {
-		return nil, err
-	}
+func (s *Server) readRequestBody(cc codec.Codec, h *codec.Header) (*request, error) {
 	req := &request{header: h}
+	var err error
 	req.svc, req.mtype, err = s.findService(h.ServerMethod)
 	if err != nil {
 		return req, err
 	}
 
 	req.argv = req.mtype.newArgv()
-	req.reply = req.mtype.newReplyv()
+	if !req.mtype.IsStream {
+		req.reply = req.mtype.newReplyv()
+	}
 
 	// make sure that argvi is a pointer, ReadBody need a pointer as parameter
 	argvi := req.argv.Interface()
@@ -166,11 +267,40 @@ func (s *Server) readRequestHeader(cc codec.Codec) (*codec.Header, error) {
 	return &h, nil
 }
 
-func (s *Server) handleRequest(cc codec.Codec, req *request, sending *sync.Mutex, wg *sync.WaitGroup) {
+func (s *Server) handleRequest(ctx context.Context, cc codec.Codec, req *request, sending *sync.Mutex, wg *sync.WaitGroup) {
 	defer wg.Done()
 	log.Println("***", req.header, req.argv)
 
-	err := req.svc.call(req.mtype, req.argv, req.reply)
+	if req.mtype.IsStream {
+		stream := &Stream{cc: cc, sending: sending, header: *req.header}
+		var err error
+		if req.mtype.ChanElemType != nil {
+			err = serviceStream{req.svc}.callChan(req.mtype, req.argv, stream)
+		} else {
+			err = serviceStream{req.svc}.call(req.mtype, req.argv, stream)
+		}
+		if err != nil {
+			req.header.Error = err.Error()
+		}
+		req.header.Flags = 0 // clear, this is the terminating frame
+		s.sendResponse(cc, req.header, invalidRequest, sending)
+		return
+	}
+
+	terminal := func(ctx context.Context, _ *Request, reply interface{}) error {
+		replyv := reflect.ValueOf(reply)
+		if req.mtype.NeedsContext {
+			return req.svc.callContext(ctx, req.mtype, req.argv, replyv)
+		}
+		return req.svc.call(req.mtype, req.argv, replyv)
+	}
+	pubReq := &Request{
+		ServiceName: req.svc.name,
+		MethodName:  req.mtype.method.Name,
+		Header:      req.header,
+		Arg:         req.argv.Interface(),
+	}
+	err := s.chain(terminal)(ctx, pubReq, req.reply.Interface())
 	if err != nil {
 		req.header.Error = err.Error()
 		s.sendResponse(cc, req.header, invalidRequest, sending)
@@ -187,3 +317,36 @@ func (s *Server) sendResponse(cc codec.Codec, h *codec.Header, body interface{},
 		log.Println("rpc server: write response error:", err)
 	}
 }
+
+// ServeHTTP implements an http.Handler that answers RPC requests tunneled
+// over HTTP CONNECT, following the net/rpc hijacking model: it only accepts
+// CONNECT, hijacks the underlying net.Conn, and hands it to ServeConn.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "CONNECT" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = io.WriteString(w, "405 must CONNECT\n")
+		return
+	}
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		log.Print("rpc hijacking ", r.RemoteAddr, ": ", err.Error())
+		return
+	}
+	_, _ = io.WriteString(conn, "HTTP/1.0 "+connected+"\n\n")
+	s.ServeConn(conn)
+}
+
+// HandleHTTP registers an HTTP handler for RPC messages on rpcPath, and a
+// debug handler on debugPath that lists every registered service's methods
+// and how many times each has been called.
+func (s *Server) HandleHTTP(rpcPath, debugPath string) {
+	http.Handle(rpcPath, s)
+	http.Handle(debugPath, debugHTTP{s})
+}
+
+// HandleHTTP registers HTTP handlers for DefaultServer on DefaultRPCPath and
+// DefaultDebugPath. It must be called before http.Serve.
+func HandleHTTP() {
+	DefaultServer.HandleHTTP(DefaultRPCPath, DefaultDebugPath)
+}
@@ -1,18 +1,38 @@
 package server
 
 import (
+	"context"
 	"go/ast"
 	"log"
 	"reflect"
 	"sync/atomic"
 )
 
+// streamType is the reflect.Type of *Stream, used to recognize a streaming
+// method's second parameter.
+var streamType = reflect.TypeOf((*Stream)(nil))
+
+// contextType is the reflect.Type of context.Context, used to recognize a
+// cancellation-aware method's first parameter.
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// errorType is the reflect.Type every registrable method must return.
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
 // methodType represents a method.
 type methodType struct {
 	method    reflect.Method // a concrete method
 	ArgType   reflect.Type   // in args type. type here,not real value,real value is defined in req.
-	ReplyType reflect.Type   // out args type
-	numCalls  uint64         // for statistics
+	ReplyType reflect.Type   // out args type. nil for a streaming method.
+	IsStream  bool           // true if this method takes a *Stream or a chan<- T2 instead of a reply pointer
+	// ChanElemType is T2 if this is a streaming method of the chan<- T2
+	// form, and nil if it takes a *Stream instead.
+	ChanElemType reflect.Type
+	// NeedsContext is true if this method's first parameter is a
+	// context.Context, so the server must dispatch through callContext and
+	// cancel it when the client sends a Cancel frame for this call.
+	NeedsContext bool
+	numCalls     uint64 // for statistics
 }
 
 // NumCalls return how many times this method is called.
@@ -70,25 +90,71 @@ func (s *service) registerMethods() {
 	for i := 0; i < s.typ.NumMethod(); i++ {
 		method := s.typ.Method(i)
 		mtype := method.Type
-		// in args=3,out args=1
-		if mtype.NumIn() != 3 || mtype.NumOut() != 1 {
-			continue
-		}
-		// return val should be error type
-		if mtype.Out(0) != reflect.TypeOf((*error)(nil)).Elem() {
+		if mtype.NumOut() != 1 || mtype.Out(0) != errorType {
 			continue
 		}
-		argType, replyType := mtype.In(1), mtype.In(2)
-		// Type should be exported, Upper case struct or built-in type.
-		if !isExportedOrBuildType(argType) || !isExportedOrBuildType(replyType) {
-			continue
-		}
-		s.method[method.Name] = &methodType{
-			method:    method,
-			ArgType:   argType,
-			ReplyType: replyType,
+
+		switch mtype.NumIn() {
+		case 3:
+			// (receiver, args, reply), (receiver, args, stream) or
+			// (receiver, args, chan<- T2)
+			argType, second := mtype.In(1), mtype.In(2)
+
+			if second == streamType {
+				if !isExportedOrBuildType(argType) {
+					continue
+				}
+				s.method[method.Name] = &methodType{
+					method:   method,
+					ArgType:  argType,
+					IsStream: true,
+				}
+				log.Printf("rpc server: register stream %s.%s\n", s.name, method.Name)
+				continue
+			}
+
+			if second.Kind() == reflect.Chan && second.ChanDir() == reflect.SendDir {
+				chanElemType := second.Elem()
+				if !isExportedOrBuildType(argType) || !isExportedOrBuildType(chanElemType) {
+					continue
+				}
+				s.method[method.Name] = &methodType{
+					method:       method,
+					ArgType:      argType,
+					IsStream:     true,
+					ChanElemType: chanElemType,
+				}
+				log.Printf("rpc server: register stream %s.%s\n", s.name, method.Name)
+				continue
+			}
+
+			// Type should be exported, Upper case struct or built-in type.
+			if !isExportedOrBuildType(argType) || !isExportedOrBuildType(second) {
+				continue
+			}
+			s.method[method.Name] = &methodType{
+				method:    method,
+				ArgType:   argType,
+				ReplyType: second,
+			}
+			log.Printf("rpc server: register %s.%s\n", s.name, method.Name)
+		case 4:
+			// (receiver, ctx, args, reply)
+			if mtype.In(1) != contextType {
+				continue
+			}
+			argType, replyType := mtype.In(2), mtype.In(3)
+			if !isExportedOrBuildType(argType) || !isExportedOrBuildType(replyType) {
+				continue
+			}
+			s.method[method.Name] = &methodType{
+				method:       method,
+				ArgType:      argType,
+				ReplyType:    replyType,
+				NeedsContext: true,
+			}
+			log.Printf("rpc server: register %s.%s\n", s.name, method.Name)
 		}
-		log.Printf("rpc server: register %s.%s\n", s.name, method.Name)
 	}
 }
 
@@ -106,3 +172,63 @@ func (s *service) call(m *methodType, argv, replyv reflect.Value) error {
 	}
 	return nil
 }
+
+// callContext invokes a cancellation-aware method, one that takes a
+// context.Context before its args, passing ctx through so the server can
+// abort it when the client sends a Cancel frame for this call's Seq.
+func (s *service) callContext(ctx context.Context, m *methodType, argv, replyv reflect.Value) error {
+	atomic.AddUint64(&m.numCalls, 1)
+	f := m.method.Func
+	returnValues := f.Call([]reflect.Value{s.receiver, reflect.ValueOf(ctx), argv, replyv})
+	if errInter := returnValues[0].Interface(); errInter != nil {
+		return errInter.(error)
+	}
+	return nil
+}
+
+// serviceStream holds the call path for a streaming method: instead of a
+// reply pointer, the method gets a *Stream it can Send on any number of
+// times before returning.
+type serviceStream struct {
+	*service
+}
+
+func (s serviceStream) call(m *methodType, argv reflect.Value, stream *Stream) error {
+	atomic.AddUint64(&m.numCalls, 1)
+	f := m.method.Func
+	returnValues := f.Call([]reflect.Value{s.receiver, argv, reflect.ValueOf(stream)})
+	if errInter := returnValues[0].Interface(); errInter != nil {
+		return errInter.(error)
+	}
+	return nil
+}
+
+// callChan runs a chan<- T2 streaming method: the method sends each reply
+// on ch and closes it once done, then returns its error. callChan forwards
+// every value received on ch to stream as an intermediate frame, and
+// returns the method's error once ch is closed and the method has returned.
+func (s serviceStream) callChan(m *methodType, argv reflect.Value, stream *Stream) error {
+	ch := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, m.ChanElemType), 0)
+
+	errc := make(chan error, 1)
+	go func() {
+		atomic.AddUint64(&m.numCalls, 1)
+		f := m.method.Func
+		returnValues := f.Call([]reflect.Value{s.receiver, argv, ch})
+		if errInter := returnValues[0].Interface(); errInter != nil {
+			errc <- errInter.(error)
+			return
+		}
+		errc <- nil
+	}()
+
+	for {
+		v, ok := ch.Recv()
+		if !ok {
+			return <-errc
+		}
+		if err := stream.Send(v.Interface()); err != nil {
+			return err
+		}
+	}
+}
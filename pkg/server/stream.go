@@ -0,0 +1,30 @@
+package server
+
+import (
+	"sync"
+
+	"rpc-go/pkg/codec"
+)
+
+// Stream is handed to a streaming service method, one whose signature is
+// func (t *T) Method(args T1, stream *server.Stream) error, so it can push
+// any number of replies back to the caller before returning. The method's
+// own return value (nil or an error) terminates the stream.
+type Stream struct {
+	cc      codec.Codec
+	sending *sync.Mutex
+	header  codec.Header // Seq/ServerMethod shared by every frame of this call
+}
+
+// Send encodes v and writes it to the client as an intermediate reply. It
+// is safe to call from the goroutine running the streaming method only;
+// it serializes under the same mutex Server uses for every write on this
+// connection.
+func (s *Stream) Send(v interface{}) error {
+	s.sending.Lock()
+	defer s.sending.Unlock()
+
+	h := s.header
+	h.Flags = codec.FlagStreamMore
+	return s.cc.Write(&h, v)
+}
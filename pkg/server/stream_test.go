@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"rpc-go/pkg/client"
+)
+
+// ChanCounter is a streaming service using the chan<- T2 form instead of
+// *Stream: it sends one reply per unit up to args.A, then closes ch.
+type ChanCounter int
+
+func (ChanCounter) Count(args DebugArgs, ch chan<- int) error {
+	for i := 1; i <= args.A; i++ {
+		ch <- i
+	}
+	close(ch)
+	return nil
+}
+
+func TestChanStreamingMethod(t *testing.T) {
+	srv := NewServer()
+	if err := srv.Registry(new(ChanCounter)); err != nil {
+		t.Fatalf("Registry: %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	go srv.Accept(l)
+
+	c, err := client.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	replyChan := make(chan int, 10)
+	call := c.StreamGo("ChanCounter.Count", DebugArgs{A: 3}, replyChan, nil)
+
+	var got []int
+	for v := range replyChan {
+		got = append(got, v)
+	}
+	select {
+	case <-call.Done:
+	case <-time.After(time.Second):
+		t.Fatal("call never finished after replyChan closed")
+	}
+	if call.Error != nil {
+		t.Fatalf("call error: %v", call.Error)
+	}
+
+	want := []int{1, 2, 3}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}